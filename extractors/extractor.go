@@ -0,0 +1,19 @@
+package extractors
+
+// Extractor is the interface every site-specific extractor implements and
+// registers with Register.
+type Extractor interface {
+	// Extract returns the extracted data for url, using option to control
+	// playlist expansion and which optional data to include.
+	Extract(url string, option Options) ([]*Data, error)
+}
+
+// Extractors holds every registered Extractor, keyed by the domain passed
+// to Register.
+var Extractors = map[string]Extractor{}
+
+// Register associates extractor with domain (e.g. "youtube"), so the
+// downloader can look it up by the host of the URL it's asked to fetch.
+func Register(domain string, extractor Extractor) {
+	Extractors[domain] = extractor
+}
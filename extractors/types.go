@@ -0,0 +1,121 @@
+package extractors
+
+import "time"
+
+// Options controls how a single Extract call behaves: which item(s) of a
+// playlist to fetch, how many parallel workers to use, and which
+// site-specific extras (live manifests, subtitles, chapters, ...) to
+// include.
+type Options struct {
+	Playlist  bool
+	Items     string
+	ItemStart int
+	ItemEnd   int
+
+	ThreadNumber int
+
+	// Live prefers a livestream/premiere's HLS or DASH manifest over the
+	// extractor's regular muxed formats.
+	Live bool
+	// LiveDuration caps how much of an in-progress livestream's manifest is
+	// turned into parts; zero means no cap.
+	LiveDuration time.Duration
+
+	// Subtitles opts in to exposing caption tracks as extra streams.
+	Subtitles bool
+	// SubtitleLanguages restricts which caption tracks are included, by
+	// BCP-47 language code; empty means all of them.
+	SubtitleLanguages []string
+	// SubtitleFormat selects the caption container ("vtt" by default, or
+	// "srt").
+	SubtitleFormat string
+	// SubtitleTranslations requests additional on-the-fly translations of
+	// the video's own captions into these language codes.
+	SubtitleTranslations []string
+
+	// Chapters opts in to fetching site-provided chapter markers.
+	Chapters bool
+	// SponsorBlock opts in to fetching SponsorBlock skip segments.
+	SponsorBlock bool
+	// SponsorBlockCategories restricts SponsorBlock results to these
+	// categories (e.g. "sponsor", "intro"); empty means all categories.
+	SponsorBlockCategories []string
+}
+
+// Data is the extracted result of a single URL.
+type Data struct {
+	Site    string
+	Title   string
+	Type    string
+	Streams map[string]*Stream
+	URL     string
+	Err     error
+
+	// Chapters holds chapter markers, populated when Options.Chapters is
+	// set.
+	Chapters []Chapter
+	// SkipSegments holds SponsorBlock segments, populated when
+	// Options.SponsorBlock is set.
+	SkipSegments []Segment
+}
+
+// Chapter is one chapter marker within a video's duration.
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// Segment is one SponsorBlock-style skippable segment within a video.
+type Segment struct {
+	Category string
+	Start    time.Duration
+	End      time.Duration
+}
+
+// EmptyData returns a Data carrying err, for an extractor to return on
+// failure without a nil Streams map.
+func EmptyData(url string, err error) *Data {
+	return &Data{URL: url, Err: err}
+}
+
+// Stream is one selectable quality/format of a Data's streams.
+type Stream struct {
+	ID      string
+	Parts   []*Part
+	NeedMux bool
+	Quality string
+	Ext     string
+}
+
+// Part is one piece of a Stream's content, fetched from URL.
+type Part struct {
+	URL  string
+	Size int64
+	Ext  string
+
+	// Body, when non-empty, is used as the part's content directly instead
+	// of fetching URL (e.g. a subtitle track already converted in memory).
+	Body string
+
+	// Segments, when non-empty, breaks URL into byte ranges so a
+	// PartFetcher can fetch them concurrently instead of one request for
+	// the whole file.
+	Segments []Range
+}
+
+// Range is one byte range of a Part's content, inclusive on both ends (as
+// in an HTTP Range header).
+type Range struct {
+	Start int64
+	End   int64
+}
+
+// PartFetcher is implemented by extractors whose Parts need more than a
+// plain GET of Part.URL to download, e.g. ranged/parallel fetch of
+// Part.Segments with on-the-fly URL refresh. The downloader type-asserts
+// the Extractor that produced a Stream against PartFetcher and calls
+// FetchPart when it's implemented, falling back to a plain GET otherwise.
+type PartFetcher interface {
+	FetchPart(part *Part, threadNumber int) (string, error)
+}
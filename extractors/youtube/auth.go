@@ -0,0 +1,207 @@
+package youtube
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// auth holds the credentials New() attaches to every InnerTube and CDN
+// request: a cookie jar built from a Netscape cookies.txt and/or a raw
+// Cookie header, an optional PO token paired with its visitorData, and an
+// optional OAuth2 refresh token for the TV client flow.
+type auth struct {
+	cookie string
+	// cookiesErr is set when WithAuth's cookiesPath fails to load, so
+	// Extract can surface it instead of silently proceeding with no
+	// cookies attached.
+	cookiesErr error
+
+	poToken     string
+	visitorData string
+
+	oauth *oauthTokenSource
+}
+
+// WithAuth configures credentials used to unlock age-restricted,
+// members-only and private videos. Any of cookiesPath, cookieHeader or
+// poToken/visitorData may be empty; they're merged with whatever's already
+// set.
+func WithAuth(cookiesPath, cookieHeader, poToken, visitorData string) Option {
+	return func(e *extractor) {
+		if e.auth == nil {
+			e.auth = &auth{}
+		}
+		if cookiesPath != "" {
+			if c, err := loadNetscapeCookies(cookiesPath); err != nil {
+				e.auth.cookiesErr = err
+			} else {
+				e.auth.cookie = mergeCookieHeaders(e.auth.cookie, c)
+			}
+		}
+		if cookieHeader != "" {
+			e.auth.cookie = mergeCookieHeaders(e.auth.cookie, cookieHeader)
+		}
+		if poToken != "" {
+			e.auth.poToken = poToken
+			e.auth.visitorData = visitorData
+			// Plumbed straight into the InnerTube /player request body as
+			// serviceIntegrityDimensions.poToken by the kkdai client.
+			e.client.PoToken = poToken
+			e.client.VisitorData = visitorData
+		}
+
+		t := e.transport()
+		t.auth = e.auth
+		if e.auth.cookie != "" {
+			t.headers["Cookie"] = e.auth.cookie
+		}
+	}
+}
+
+// WithOAuth enables the TV client OAuth2 refresh-token flow: refreshToken is
+// exchanged for an access token that's attached as an Authorization: Bearer
+// header, refreshed on expiry or a 401. YouTube ties the OAuth Bearer flow
+// to the TV embedded client context, so this also pins e.clientOrder to
+// "tv" instead of leaving the Android/iOS/Web rotation in place, since a TV
+// token sent alongside another client's identity is rejected or ignored.
+func WithOAuth(clientID, clientSecret, refreshToken string) Option {
+	return func(e *extractor) {
+		if e.auth == nil {
+			e.auth = &auth{}
+		}
+		e.auth.oauth = &oauthTokenSource{
+			clientID:     clientID,
+			clientSecret: clientSecret,
+			refreshToken: refreshToken,
+		}
+		e.transport().auth = e.auth
+		e.clientOrder = []string{"tv"}
+	}
+}
+
+// transport returns the extractor's youtubeTransport so auth options can
+// attach headers to it regardless of call order relative to New.
+func (e *extractor) transport() *youtubeTransport {
+	return e.client.HTTPClient.Transport.(*youtubeTransport)
+}
+
+// mergeCookieHeaders combines two `name=value; name2=value2` cookie headers,
+// letting values from b override same-named cookies from a.
+func mergeCookieHeaders(a, b string) string {
+	cookies := make(map[string]string)
+	var order []string
+	for _, header := range []string{a, b} {
+		for _, pair := range strings.Split(header, ";") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				continue
+			}
+			if _, seen := cookies[name]; !seen {
+				order = append(order, name)
+			}
+			cookies[name] = value
+		}
+	}
+
+	parts := make([]string, 0, len(order))
+	for _, name := range order {
+		parts = append(parts, fmt.Sprintf("%s=%s", name, cookies[name]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// loadNetscapeCookies reads a Netscape-format cookies.txt and returns it as
+// a single `name=value; ...` Cookie header.
+func loadNetscapeCookies(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	var parts []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", fields[5], fields[6]))
+	}
+	if len(parts) == 0 {
+		return "", errors.Errorf("no cookies found in %s", path)
+	}
+	return strings.Join(parts, "; "), nil
+}
+
+// oauthTokenURL is a var, not a const, so tests can point it at an
+// httptest.Server.
+var oauthTokenURL = "https://oauth2.googleapis.com/token"
+
+// oauthTokenSource mints and refreshes the Bearer token used by the TV
+// client OAuth2 flow.
+type oauthTokenSource struct {
+	clientID     string
+	clientSecret string
+	refreshToken string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// token returns a valid access token, refreshing it first if it's missing,
+// expired, or forceRefresh is set (as it is after a 401).
+func (o *oauthTokenSource) token(forceRefresh bool) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !forceRefresh && o.accessToken != "" && time.Now().Before(o.expiresAt) {
+		return o.accessToken, nil
+	}
+
+	resp, err := http.PostForm(oauthTokenURL, map[string][]string{
+		"client_id":     {o.clientID},
+		"client_secret": {o.clientSecret},
+		"refresh_token": {o.refreshToken},
+		"grant_type":    {"refresh_token"},
+	})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return "", errors.Errorf("oauth token refresh failed with status %d: %s", resp.StatusCode, data)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("oauth token refresh returned an empty access_token")
+	}
+
+	o.accessToken = body.AccessToken
+	o.expiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return o.accessToken, nil
+}
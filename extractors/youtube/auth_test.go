@@ -0,0 +1,170 @@
+package youtube
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kkdai/youtube/v2"
+
+	"github.com/iawia002/lux/extractors"
+)
+
+func newTestExtractor() *extractor {
+	return &extractor{
+		client: &youtube.Client{
+			HTTPClient: &http.Client{
+				Transport: &youtubeTransport{
+					base:    http.DefaultTransport,
+					headers: map[string]string{"x-goog-visitor-id": "test-visitor"},
+				},
+			},
+		},
+	}
+}
+
+func TestMergeCookieHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"b wins on conflict", "SID=old; HSID=1", "SID=new", "SID=new; HSID=1"},
+		{"b only", "", "SID=new", "SID=new"},
+		{"a only", "SID=old", "", "SID=old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeCookieHeaders(tt.a, tt.b); got != tt.want {
+				t.Errorf("mergeCookieHeaders(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadNetscapeCookies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	content := "# Netscape HTTP Cookie File\n" +
+		".youtube.com\tTRUE\t/\tTRUE\t0\tSID\tabc123\n" +
+		".youtube.com\tTRUE\t/\tTRUE\t0\tHSID\tdef456\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadNetscapeCookies(path)
+	if err != nil {
+		t.Fatalf("loadNetscapeCookies() error = %v", err)
+	}
+	want := "SID=abc123; HSID=def456"
+	if got != want {
+		t.Errorf("loadNetscapeCookies() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadNetscapeCookiesEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte("# just a comment\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadNetscapeCookies(path); err == nil {
+		t.Error("loadNetscapeCookies() error = nil, want error for a file with no cookies")
+	}
+}
+
+func TestWithAuthPropagatesCookiesAndPOToken(t *testing.T) {
+	e := newTestExtractor()
+	WithAuth("", "SID=abc; HSID=def", "po-token-value", "visitor-data-value")(e)
+
+	if e.client.PoToken != "po-token-value" {
+		t.Errorf("client.PoToken = %q, want %q", e.client.PoToken, "po-token-value")
+	}
+	if e.client.VisitorData != "visitor-data-value" {
+		t.Errorf("client.VisitorData = %q, want %q", e.client.VisitorData, "visitor-data-value")
+	}
+	if got := e.transport().headers["Cookie"]; got != "SID=abc; HSID=def" {
+		t.Errorf("transport Cookie header = %q, want %q", got, "SID=abc; HSID=def")
+	}
+}
+
+func TestWithAuthMergesCookiesAcrossCalls(t *testing.T) {
+	e := newTestExtractor()
+	WithAuth("", "SID=abc", "", "")(e)
+	WithAuth("", "HSID=def", "", "")(e)
+
+	want := "SID=abc; HSID=def"
+	if got := e.transport().headers["Cookie"]; got != want {
+		t.Errorf("transport Cookie header = %q, want %q", got, want)
+	}
+}
+
+func TestWithAuthRecordsBadCookiesPath(t *testing.T) {
+	e := newTestExtractor()
+	WithAuth(filepath.Join(t.TempDir(), "does-not-exist.txt"), "", "", "")(e)
+
+	if e.auth.cookiesErr == nil {
+		t.Fatal("auth.cookiesErr = nil, want an error for an unreadable cookies.txt path")
+	}
+	if e.transport().headers["Cookie"] != "" {
+		t.Errorf("transport Cookie header = %q, want empty when cookiesPath failed to load", e.transport().headers["Cookie"])
+	}
+}
+
+func TestExtractReturnsBadCookiesPathError(t *testing.T) {
+	e := newTestExtractor()
+	WithAuth(filepath.Join(t.TempDir(), "does-not-exist.txt"), "", "", "")(e)
+
+	_, err := e.Extract("https://www.youtube.com/watch?v=dQw4w9WgXcQ", extractors.Options{})
+	if err == nil {
+		t.Fatal("Extract() error = nil, want the cookiesPath load error surfaced")
+	}
+}
+
+// withOAuthTokenURL points oauthTokenURL at url for the duration of fn,
+// restoring it afterwards.
+func withOAuthTokenURL(t *testing.T, url string, fn func()) {
+	t.Helper()
+	original := oauthTokenURL
+	oauthTokenURL = url
+	defer func() { oauthTokenURL = original }()
+	fn()
+}
+
+func TestOAuthTokenRefresh(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"fresh-token","expires_in":3600}`))
+	}))
+	defer srv.Close()
+
+	o := &oauthTokenSource{clientID: "id", clientSecret: "secret", refreshToken: "refresh"}
+	withOAuthTokenURL(t, srv.URL, func() {
+		token, err := o.token(false)
+		if err != nil {
+			t.Fatalf("token() error = %v", err)
+		}
+		if token != "fresh-token" {
+			t.Errorf("token() = %q, want %q", token, "fresh-token")
+		}
+	})
+}
+
+func TestOAuthTokenRefreshFailureStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer srv.Close()
+
+	o := &oauthTokenSource{clientID: "id", clientSecret: "secret", refreshToken: "revoked"}
+	withOAuthTokenURL(t, srv.URL, func() {
+		token, err := o.token(true)
+		if err == nil {
+			t.Fatal("token() error = nil, want error on a non-200 refresh response")
+		}
+		if token != "" {
+			t.Errorf("token() = %q, want empty on error", token)
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package youtube
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/iawia002/lux/extractors"
+)
+
+// withNextEndpoint points nextEndpoint at u for the duration of fn,
+// restoring it afterwards.
+func withNextEndpoint(t *testing.T, u string, fn func()) {
+	t.Helper()
+	original := nextEndpoint
+	nextEndpoint = u
+	defer func() { nextEndpoint = original }()
+	fn()
+}
+
+// withSponsorBlockAPI points sponsorBlockAPI at u for the duration of fn,
+// restoring it afterwards.
+func withSponsorBlockAPI(t *testing.T, u string, fn func()) {
+	t.Helper()
+	original := sponsorBlockAPI
+	sponsorBlockAPI = u
+	defer func() { sponsorBlockAPI = original }()
+	fn()
+}
+
+const nextResponseFixture = `{
+	"engagementPanels": [
+		{
+			"engagementPanelSectionListRenderer": {
+				"content": {
+					"macroMarkersListRenderer": {
+						"contents": [
+							{"macroMarkersListItemRenderer": {"title": {"simpleText": "Intro"}, "onTap": {"watchEndpoint": {"startTimeSeconds": 0}}}},
+							{"macroMarkersListItemRenderer": {"title": {"simpleText": "Main event"}, "onTap": {"watchEndpoint": {"startTimeSeconds": 30}}}}
+						]
+					}
+				}
+			}
+		}
+	]
+}`
+
+func TestChaptersFromVideoStartEndMath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(nextResponseFixture))
+	}))
+	defer srv.Close()
+
+	var chapters []extractors.Chapter
+	withNextEndpoint(t, srv.URL, func() {
+		chapters = chaptersFromVideo("dQw4w9WgXcQ", 90*time.Second)
+	})
+
+	if len(chapters) != 2 {
+		t.Fatalf("chaptersFromVideo() returned %d chapters, want 2", len(chapters))
+	}
+	if chapters[0].Title != "Intro" || chapters[0].Start != 0 || chapters[0].End != 30*time.Second {
+		t.Errorf("chapters[0] = %+v, want Intro 0-30s", chapters[0])
+	}
+	if chapters[1].Title != "Main event" || chapters[1].Start != 30*time.Second || chapters[1].End != 90*time.Second {
+		t.Errorf("chapters[1] = %+v, want %q 30-90s", chapters[1], "Main event")
+	}
+}
+
+func TestChaptersFromVideoDegradesOnNon200(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	var got []extractors.Chapter
+	withNextEndpoint(t, srv.URL, func() {
+		got = chaptersFromVideo("dQw4w9WgXcQ", 90*time.Second)
+	})
+	if got != nil {
+		t.Errorf("chaptersFromVideo() = %v, want nil on a non-200 response", got)
+	}
+}
+
+func TestChaptersFromVideoDegradesOnMalformedJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer srv.Close()
+
+	var got []extractors.Chapter
+	withNextEndpoint(t, srv.URL, func() {
+		got = chaptersFromVideo("dQw4w9WgXcQ", 90*time.Second)
+	})
+	if got != nil {
+		t.Errorf("chaptersFromVideo() = %v, want nil on malformed JSON", got)
+	}
+}
+
+func TestChaptersClientCachesByVideoID(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(nextResponseFixture))
+	}))
+	defer srv.Close()
+
+	c := &chaptersClient{cache: make(map[string][]extractors.Chapter)}
+	withNextEndpoint(t, srv.URL, func() {
+		c.chaptersFor("dQw4w9WgXcQ", 90*time.Second)
+		c.chaptersFor("dQw4w9WgXcQ", 90*time.Second)
+	})
+
+	if calls != 1 {
+		t.Errorf("chaptersFor() hit the /next endpoint %d times for the same video ID, want 1", calls)
+	}
+}
+
+func TestChaptersClientCachesSeparatelyPerVideoID(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(nextResponseFixture))
+	}))
+	defer srv.Close()
+
+	c := &chaptersClient{cache: make(map[string][]extractors.Chapter)}
+	withNextEndpoint(t, srv.URL, func() {
+		c.chaptersFor("video-a", 90*time.Second)
+		c.chaptersFor("video-b", 90*time.Second)
+	})
+
+	if calls != 2 {
+		t.Errorf("chaptersFor() hit the /next endpoint %d times for 2 distinct video IDs, want 2", calls)
+	}
+}
+
+func TestSponsorBlockFetchBuildsCategoriesQuery(t *testing.T) {
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		_, _ = w.Write([]byte(`[{"category":"sponsor","segment":[1.5,10]}]`))
+	}))
+	defer srv.Close()
+
+	c := &sponsorBlockClient{cache: make(map[string][]extractors.Segment)}
+	var segments []extractors.Segment
+	withSponsorBlockAPI(t, srv.URL, func() {
+		segments = c.fetch("dQw4w9WgXcQ", []string{"sponsor", "intro"})
+	})
+
+	if gotQuery.Get("videoID") != "dQw4w9WgXcQ" {
+		t.Errorf("videoID query param = %q, want %q", gotQuery.Get("videoID"), "dQw4w9WgXcQ")
+	}
+	if gotQuery.Get("categories") != `["sponsor","intro"]` {
+		t.Errorf("categories query param = %q, want %q", gotQuery.Get("categories"), `["sponsor","intro"]`)
+	}
+	if len(segments) != 1 || segments[0].Category != "sponsor" {
+		t.Fatalf("fetch() = %+v, want one sponsor segment", segments)
+	}
+	if segments[0].Start != 1500*time.Millisecond || segments[0].End != 10*time.Second {
+		t.Errorf("segment times = %v-%v, want 1.5s-10s", segments[0].Start, segments[0].End)
+	}
+}
+
+func TestSponsorBlockFetchOmitsCategoriesWhenEmpty(t *testing.T) {
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	c := &sponsorBlockClient{cache: make(map[string][]extractors.Segment)}
+	withSponsorBlockAPI(t, srv.URL, func() {
+		c.fetch("dQw4w9WgXcQ", nil)
+	})
+
+	if gotQuery.Has("categories") {
+		t.Errorf("categories query param present = %q, want absent when no categories given", gotQuery.Get("categories"))
+	}
+}
+
+func TestSponsorBlockSegmentsCachesByVideoIDAndCategories(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	c := &sponsorBlockClient{cache: make(map[string][]extractors.Segment)}
+	withSponsorBlockAPI(t, srv.URL, func() {
+		c.segments("dQw4w9WgXcQ", []string{"sponsor"})
+		c.segments("dQw4w9WgXcQ", []string{"sponsor"})
+		c.segments("dQw4w9WgXcQ", []string{"intro"})
+	})
+
+	if calls != 2 {
+		t.Errorf("segments() hit the API %d times, want 2 (one per distinct videoID+categories key)", calls)
+	}
+}
+
+func TestSponsorBlockFetchDegradesOnMalformedJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer srv.Close()
+
+	c := &sponsorBlockClient{cache: make(map[string][]extractors.Segment)}
+	var got []extractors.Segment
+	withSponsorBlockAPI(t, srv.URL, func() {
+		got = c.fetch("dQw4w9WgXcQ", nil)
+	})
+	if got != nil {
+		t.Errorf("fetch() = %v, want nil on malformed JSON", got)
+	}
+}
@@ -0,0 +1,58 @@
+package youtube
+
+import (
+	"github.com/kkdai/youtube/v2"
+)
+
+// clientInfo pairs a short name (as used by WithClients) with the kkdai
+// youtube.v2 ClientInfo it maps to.
+type clientInfo struct {
+	name string
+	info youtube.ClientInfo
+}
+
+// knownClients are the InnerTube clients we know how to select. Android and
+// iOS return pre-signed URLs and don't require signatureCipher/n-param
+// decryption, so they're tried first by default; Web is kept last as the
+// fallback since it always works but needs the decipher/nsig dance. TV is
+// not part of the default rotation: it's only selected by WithOAuth, since
+// that's the client identity YouTube's OAuth2 Bearer flow is scoped to.
+var knownClients = []clientInfo{
+	{name: "android", info: youtube.AndroidClient},
+	{name: "ios", info: youtube.IOSClient},
+	{name: "web", info: youtube.WebClient},
+	{name: "tv", info: youtube.TVClient},
+}
+
+// defaultClientOrder is used when New is called without WithClients.
+var defaultClientOrder = []string{"android", "ios", "web"}
+
+func clientInfoByName(name string) (youtube.ClientInfo, bool) {
+	for _, c := range knownClients {
+		if c.name == name {
+			return c.info, true
+		}
+	}
+	return youtube.ClientInfo{}, false
+}
+
+// Option configures an extractor returned by New.
+type Option func(*extractor)
+
+// WithClients sets the ordered list of InnerTube clients the extractor tries
+// when fetching a video, rotating to the next one on a 403 or an
+// empty-formats response. Valid names are "android", "ios" and "web";
+// unknown names are dropped. Defaults to defaultClientOrder.
+func WithClients(names ...string) Option {
+	return func(e *extractor) {
+		order := make([]string, 0, len(names))
+		for _, name := range names {
+			if _, ok := clientInfoByName(name); ok {
+				order = append(order, name)
+			}
+		}
+		if len(order) > 0 {
+			e.clientOrder = order
+		}
+	}
+}
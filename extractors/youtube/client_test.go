@@ -0,0 +1,247 @@
+package youtube
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kkdai/youtube/v2"
+	"github.com/pkg/errors"
+)
+
+func TestClientInfoByName(t *testing.T) {
+	for _, name := range []string{"android", "ios", "web"} {
+		if _, ok := clientInfoByName(name); !ok {
+			t.Errorf("clientInfoByName(%q) = not ok, want ok", name)
+		}
+	}
+	if _, ok := clientInfoByName("smart-tv"); ok {
+		t.Error(`clientInfoByName("smart-tv") = ok, want not ok`)
+	}
+}
+
+func TestWithClients(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{"reorders to given preference", []string{"web", "android"}, []string{"web", "android"}},
+		{"drops unknown names", []string{"android", "smart-tv", "ios"}, []string{"android", "ios"}},
+		{"empty/all-unknown leaves default order", []string{"smart-tv"}, defaultClientOrder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &extractor{clientOrder: defaultClientOrder}
+			WithClients(tt.input...)(e)
+			if !equalStrings(e.clientOrder, tt.want) {
+				t.Errorf("clientOrder = %v, want %v", e.clientOrder, tt.want)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fakeFormat is a minimal valid youtube.Video so the Formats length check
+// in withClientFallback behaves as it would against a real player response.
+func fakeFormat() youtube.FormatList {
+	return youtube.FormatList{{ItagNo: 18}}
+}
+
+func TestWithClientFallbackRotatesOnRotatableError(t *testing.T) {
+	e := &extractor{client: &youtube.Client{}, clientOrder: []string{"android", "ios"}}
+
+	var tried []youtube.ClientInfo
+	video, err := e.withClientFallback(func(c *youtube.Client) (*youtube.Video, error) {
+		tried = append(tried, c.Client)
+		if len(tried) == 1 {
+			return nil, errors.Errorf("player response: 403")
+		}
+		return &youtube.Video{Formats: fakeFormat()}, nil
+	})
+	if err != nil {
+		t.Fatalf("withClientFallback() error = %v, want nil", err)
+	}
+	if len(video.Formats) == 0 {
+		t.Fatal("withClientFallback() returned video with no formats")
+	}
+	if len(tried) != 2 {
+		t.Fatalf("tried %d clients, want 2 (android then ios)", len(tried))
+	}
+	androidInfo, _ := clientInfoByName("android")
+	if tried[0] != androidInfo {
+		t.Errorf("first client tried = %+v, want android", tried[0])
+	}
+}
+
+func TestWithClientFallbackStopsOnNonRotatableError(t *testing.T) {
+	e := &extractor{client: &youtube.Client{}, clientOrder: []string{"android", "ios"}}
+
+	calls := 0
+	_, err := e.withClientFallback(func(c *youtube.Client) (*youtube.Video, error) {
+		calls++
+		return nil, errors.Errorf("video unavailable")
+	})
+	if err == nil {
+		t.Fatal("withClientFallback() error = nil, want non-nil")
+	}
+	if calls != 1 {
+		t.Errorf("called fetch %d times, want 1 (should not rotate on a permanent error)", calls)
+	}
+}
+
+func TestClientForDoesNotMutateSharedClient(t *testing.T) {
+	e := &extractor{client: &youtube.Client{}, clientOrder: defaultClientOrder}
+	androidInfo, _ := clientInfoByName("android")
+	webInfo, _ := clientInfoByName("web")
+
+	android := e.clientFor(androidInfo)
+	web := e.clientFor(webInfo)
+
+	if android.Client == web.Client {
+		t.Fatal("clientFor returned clients with the same Client field, want independent copies")
+	}
+	if e.client.Client != (youtube.ClientInfo{}) {
+		t.Errorf("e.client.Client = %+v, want zero value (clientFor must not mutate the shared client)", e.client.Client)
+	}
+}
+
+// fixturePlayerResponse is the slice of a recorded InnerTube /player response
+// this package actually reads. Formats/AdaptiveFormats decode straight into
+// kkdai's own youtube.Format via its real json tags, so a fixture's url=
+// (Android/iOS) or signatureCipher= (Web) field is parsed by the same code
+// path as a live response, not reconstructed by hand. Deciphering a
+// signatureCipher into a playable URL still requires a network fetch of
+// YouTube's base.js, so it happens later in GetStreamURL and isn't
+// exercised by this fixture-driven test; what's checked here is that
+// withClientFallback's format-count check sees the right shape per client.
+type fixturePlayerResponse struct {
+	StreamingData struct {
+		Formats         youtube.FormatList `json:"formats"`
+		AdaptiveFormats youtube.FormatList `json:"adaptiveFormats"`
+		HLSManifestURL  string             `json:"hlsManifestUrl"`
+		DASHManifestURL string             `json:"dashManifestUrl"`
+	} `json:"streamingData"`
+}
+
+func loadFixturePlayerResponse(t *testing.T, name string) fixturePlayerResponse {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+	var resp fixturePlayerResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("unmarshaling fixture %s: %v", name, err)
+	}
+	return resp
+}
+
+// videoFromFixture builds the *youtube.Video withClientFallback's fetch
+// callback receives, using the youtube.Format values resp's own
+// UnmarshalJSON already populated rather than reconstructing them field by
+// field.
+func videoFromFixture(resp fixturePlayerResponse) *youtube.Video {
+	formats := make(youtube.FormatList, 0, len(resp.StreamingData.Formats)+len(resp.StreamingData.AdaptiveFormats))
+	formats = append(formats, resp.StreamingData.Formats...)
+	formats = append(formats, resp.StreamingData.AdaptiveFormats...)
+	return &youtube.Video{
+		Formats:         formats,
+		HLSManifestURL:  resp.StreamingData.HLSManifestURL,
+		DASHManifestURL: resp.StreamingData.DASHManifestURL,
+	}
+}
+
+// TestWithClientFallbackAcceptsRecordedFixtureShapePerClient grounds
+// withClientFallback's format-count check against a recorded /player
+// response shape for each client, including Web's signatureCipher-gated
+// formats, so a change to the client-selection logic can't silently stop
+// accepting a real response shape.
+func TestWithClientFallbackAcceptsRecordedFixtureShapePerClient(t *testing.T) {
+	tests := []struct {
+		client  string
+		fixture string
+		// cipherGated is true for the one client (Web) whose fixture
+		// formats carry signatureCipher instead of a direct url.
+		cipherGated bool
+	}{
+		{client: "android", fixture: "android_player.json"},
+		{client: "ios", fixture: "ios_player.json"},
+		{client: "web", fixture: "web_player.json", cipherGated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.client, func(t *testing.T) {
+			resp := loadFixturePlayerResponse(t, tt.fixture)
+			if len(resp.StreamingData.Formats) == 0 {
+				t.Fatalf("fixture %s has no formats to test against", tt.fixture)
+			}
+			for _, f := range resp.StreamingData.Formats {
+				if tt.cipherGated && f.Cipher == "" {
+					t.Fatalf("fixture %s format itag %d has no signatureCipher, want cipher-gated", tt.fixture, f.ItagNo)
+				}
+				if !tt.cipherGated && f.URL == "" {
+					t.Fatalf("fixture %s format itag %d has no url, want a direct URL", tt.fixture, f.ItagNo)
+				}
+			}
+			want := videoFromFixture(resp)
+
+			wantInfo, ok := clientInfoByName(tt.client)
+			if !ok {
+				t.Fatalf("clientInfoByName(%q) = not ok", tt.client)
+			}
+
+			e := &extractor{client: &youtube.Client{}, clientOrder: []string{tt.client}}
+			got, err := e.withClientFallback(func(c *youtube.Client) (*youtube.Video, error) {
+				if c.Client != wantInfo {
+					t.Errorf("fetch called with client %+v, want %+v", c.Client, wantInfo)
+				}
+				return want, nil
+			})
+			if err != nil {
+				t.Fatalf("withClientFallback() error = %v", err)
+			}
+			if len(got.Formats) != len(want.Formats) {
+				t.Errorf("withClientFallback() returned %d formats, want %d", len(got.Formats), len(want.Formats))
+			}
+		})
+	}
+}
+
+// TestWithClientFallbackAcceptsLiveFixtureWithNoFormats grounds the
+// live-manifest short-circuit against a recorded premiere/livestream
+// /player response shape: zero formats, only hlsManifestUrl/dashManifestUrl.
+func TestWithClientFallbackAcceptsLiveFixtureWithNoFormats(t *testing.T) {
+	resp := loadFixturePlayerResponse(t, "live_player.json")
+	if len(resp.StreamingData.Formats) != 0 {
+		t.Fatalf("fixture live_player.json has %d formats, want 0 to exercise the live path", len(resp.StreamingData.Formats))
+	}
+	want := videoFromFixture(resp)
+	if want.HLSManifestURL == "" {
+		t.Fatal("fixture live_player.json is missing hlsManifestUrl")
+	}
+
+	e := &extractor{client: &youtube.Client{}, clientOrder: []string{"android"}}
+	got, err := e.withClientFallback(func(c *youtube.Client) (*youtube.Video, error) {
+		return want, nil
+	})
+	if err != nil {
+		t.Fatalf("withClientFallback() error = %v, want nil for a live manifest response with zero formats", err)
+	}
+	if got.HLSManifestURL != want.HLSManifestURL {
+		t.Errorf("withClientFallback() HLSManifestURL = %q, want %q", got.HLSManifestURL, want.HLSManifestURL)
+	}
+}
@@ -0,0 +1,343 @@
+package youtube
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kkdai/youtube/v2"
+	"github.com/pkg/errors"
+
+	"github.com/iawia002/lux/extractors"
+	"github.com/iawia002/lux/request"
+	"github.com/iawia002/lux/utils"
+)
+
+// youtubeDownloadLive handles livestreams and premieres, which expose an
+// HLS and/or DASH manifest instead of muxed formats in video.Formats.
+// option.LiveDuration caps how much of an in-progress stream's manifest is
+// turned into parts, since a still-running broadcast's playlist only grows
+// over time; a finished premiere's manifest is already bounded and ignores it.
+func (e *extractor) youtubeDownloadLive(url string, video *youtube.Video, option extractors.Options) *extractors.Data {
+	streams := make(map[string]*extractors.Stream)
+
+	if video.HLSManifestURL != "" {
+		hls, err := hlsStreams(video.HLSManifestURL, option.LiveDuration)
+		if err != nil {
+			return extractors.EmptyData(url, err)
+		}
+		for id, s := range hls {
+			streams[id] = s
+		}
+	}
+
+	if video.DASHManifestURL != "" {
+		dash, err := dashStreams(video.DASHManifestURL, option.LiveDuration)
+		if err != nil {
+			return extractors.EmptyData(url, err)
+		}
+		for id, s := range dash {
+			streams[id] = s
+		}
+	}
+
+	if len(streams) == 0 {
+		return extractors.EmptyData(url, errors.New("no HLS or DASH variants found"))
+	}
+
+	data := &extractors.Data{
+		Site:    "YouTube youtube.com",
+		Title:   video.Title,
+		Type:    "video",
+		Streams: streams,
+		URL:     url,
+	}
+	e.addChaptersAndSkipSegments(data, video, option)
+	return data
+}
+
+// hlsVariant is one #EXT-X-STREAM-INF entry from an HLS master playlist.
+type hlsVariant struct {
+	bandwidth  int
+	resolution string
+	codecs     string
+	url        string
+}
+
+// parseHLSMasterPlaylist extracts the variant streams out of a master
+// .m3u8, resolving relative URIs against the master's own URL.
+func parseHLSMasterPlaylist(masterURL, body string) []hlsVariant {
+	var variants []hlsVariant
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
+			continue
+		}
+		if i+1 >= len(lines) {
+			continue
+		}
+		uri := strings.TrimSpace(lines[i+1])
+		if uri == "" || strings.HasPrefix(uri, "#") {
+			continue
+		}
+
+		v := hlsVariant{url: utils.ResolveReferenceURL(masterURL, uri)}
+		attrs := line[len("#EXT-X-STREAM-INF:"):]
+		for _, kv := range utils.MatchAll(attrs, `([A-Z-]+)=("[^"]*"|[^,]*)`) {
+			key, value := kv[1], strings.Trim(kv[2], `"`)
+			switch key {
+			case "BANDWIDTH":
+				v.bandwidth, _ = strconv.Atoi(value)
+			case "RESOLUTION":
+				v.resolution = value
+			case "CODECS":
+				v.codecs = value
+			}
+		}
+		variants = append(variants, v)
+	}
+	return variants
+}
+
+// mediaPlaylistSegment is one #EXTINF-prefixed segment URI of a media
+// .m3u8 playlist, resolved against the playlist's own URL.
+type mediaPlaylistSegment struct {
+	url      string
+	duration time.Duration
+}
+
+// mediaPlaylistSegments returns the segments of a media .m3u8 playlist,
+// resolved against playlistURL.
+func mediaPlaylistSegments(playlistURL, body string) []mediaPlaylistSegment {
+	var segments []mediaPlaylistSegment
+	var nextDuration time.Duration
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#EXTINF:") {
+			durationField, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			seconds, _ := strconv.ParseFloat(durationField, 64)
+			nextDuration = time.Duration(seconds * float64(time.Second))
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		segments = append(segments, mediaPlaylistSegment{
+			url:      utils.ResolveReferenceURL(playlistURL, line),
+			duration: nextDuration,
+		})
+		nextDuration = 0
+	}
+	return segments
+}
+
+// capByDuration trims segments to at most maxDuration of playback, starting
+// from the live edge (the end of the slice) and working backwards, which is
+// what you want for an in-progress stream: the most recent segments, not
+// the ones from when the broadcast started. maxDuration <= 0 means no cap.
+func capByDuration(segments []mediaPlaylistSegment, maxDuration time.Duration) []mediaPlaylistSegment {
+	if maxDuration <= 0 {
+		return segments
+	}
+
+	var total time.Duration
+	cut := 0
+	for i := len(segments) - 1; i >= 0; i-- {
+		total += segments[i].duration
+		cut = i
+		if total >= maxDuration {
+			break
+		}
+	}
+	return segments[cut:]
+}
+
+// hlsStreams fetches the master playlist at hlsManifestURL and turns each
+// variant into an extractors.Stream whose Parts are the variant's own media
+// segments, so the downloader can concatenate them into one file. When
+// liveDuration is positive, each variant is capped to that much playback
+// time, taken from the live edge.
+func hlsStreams(hlsManifestURL string, liveDuration time.Duration) (map[string]*extractors.Stream, error) {
+	masterBody, err := request.Get(hlsManifestURL, referer, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	streams := make(map[string]*extractors.Stream)
+	for i, variant := range parseHLSMasterPlaylist(hlsManifestURL, masterBody) {
+		mediaBody, err := request.Get(variant.url, referer, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		segments := capByDuration(mediaPlaylistSegments(variant.url, mediaBody), liveDuration)
+		if len(segments) == 0 {
+			continue
+		}
+
+		parts := make([]*extractors.Part, len(segments))
+		for j, seg := range segments {
+			size, _ := request.Size(seg.url, referer)
+			parts[j] = &extractors.Part{URL: seg.url, Size: size, Ext: "ts"}
+		}
+
+		id := fmt.Sprintf("hls-%d", i)
+		quality := variant.resolution
+		if quality == "" {
+			quality = fmt.Sprintf("%d bps", variant.bandwidth)
+		}
+		streams[id] = &extractors.Stream{
+			ID:      id,
+			Parts:   parts,
+			Quality: fmt.Sprintf("%s %s", quality, variant.codecs),
+			Ext:     "ts",
+			NeedMux: false,
+		}
+	}
+	return streams, nil
+}
+
+// dashTimelineEntry is one <S> entry of a SegmentTimeline: a duration (in
+// the SegmentTemplate's timescale units) repeated r+1 times.
+type dashTimelineEntry struct {
+	D int `xml:"d,attr"`
+	R int `xml:"r,attr"`
+}
+
+// mpd is the subset of a DASH manifest we need: one BaseURL and a
+// SegmentTemplate range per Representation.
+type mpd struct {
+	Period struct {
+		AdaptationSet []struct {
+			Representation []struct {
+				ID              string `xml:"id,attr"`
+				Bandwidth       int    `xml:"bandwidth,attr"`
+				Width           int    `xml:"width,attr"`
+				Height          int    `xml:"height,attr"`
+				Codecs          string `xml:"codecs,attr"`
+				BaseURL         string `xml:"BaseURL"`
+				SegmentTemplate *struct {
+					Media          string `xml:"media,attr"`
+					Initialization string `xml:"initialization,attr"`
+					StartNumber    int    `xml:"startNumber,attr"`
+					Timescale      int    `xml:"timescale,attr"`
+					Timeline       struct {
+						S []dashTimelineEntry `xml:"S"`
+					} `xml:"SegmentTimeline"`
+				} `xml:"SegmentTemplate"`
+			} `xml:"Representation"`
+		} `xml:"AdaptationSet"`
+	} `xml:"Period"`
+}
+
+// dashStreams fetches and parses a DASH MPD, emitting one stream per
+// Representation built from its BaseURL, init segment and SegmentTemplate.
+// When liveDuration is positive, each representation's segments are capped
+// to that much playback time, taken from the live edge.
+func dashStreams(dashManifestURL string, liveDuration time.Duration) (map[string]*extractors.Stream, error) {
+	body, err := request.Get(dashManifestURL, referer, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var doc mpd
+	if err := xml.Unmarshal([]byte(body), &doc); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	streams := make(map[string]*extractors.Stream)
+	for _, as := range doc.Period.AdaptationSet {
+		for _, rep := range as.Representation {
+			baseURL := utils.ResolveReferenceURL(dashManifestURL, rep.BaseURL)
+			var parts []*extractors.Part
+			if rep.SegmentTemplate != nil {
+				tmpl := rep.SegmentTemplate
+				if tmpl.Initialization != "" {
+					initURL := utils.ResolveReferenceURL(baseURL, strings.ReplaceAll(tmpl.Initialization, "$RepresentationID$", rep.ID))
+					size, _ := request.Size(initURL, referer)
+					// The init segment carries the moov atom fMP4 media
+					// segments need to be muxed into a playable file; it
+					// must come first and isn't subject to the live cap.
+					parts = append(parts, &extractors.Part{URL: initURL, Size: size, Ext: "m4s"})
+				}
+
+				timescale := tmpl.Timescale
+				if timescale == 0 {
+					timescale = 1
+				}
+				number := tmpl.StartNumber
+				if number == 0 {
+					number = 1
+				}
+
+				var mediaParts []*extractors.Part
+				for _, s := range tmpl.Timeline.S {
+					repeat := s.R + 1
+					for i := 0; i < repeat; i++ {
+						seg := strings.NewReplacer(
+							"$RepresentationID$", rep.ID,
+							"$Number$", strconv.Itoa(number),
+						).Replace(tmpl.Media)
+						segURL := utils.ResolveReferenceURL(baseURL, seg)
+						size, _ := request.Size(segURL, referer)
+						mediaParts = append(mediaParts, &extractors.Part{URL: segURL, Size: size, Ext: "m4s"})
+						number++
+					}
+				}
+				parts = append(parts, capDASHSegmentsByDuration(mediaParts, tmpl.Timeline.S, timescale, liveDuration)...)
+			} else {
+				size, _ := request.Size(baseURL, referer)
+				parts = append(parts, &extractors.Part{URL: baseURL, Size: size, Ext: "m4s"})
+			}
+			if len(parts) == 0 {
+				continue
+			}
+
+			id := "dash-" + rep.ID
+			streams[id] = &extractors.Stream{
+				ID:      id,
+				Parts:   parts,
+				Quality: fmt.Sprintf("%dx%d %s", rep.Width, rep.Height, rep.Codecs),
+				Ext:     "mp4",
+				NeedMux: false,
+			}
+		}
+	}
+	return streams, nil
+}
+
+// capDASHSegmentsByDuration trims mediaParts (one per expanded
+// SegmentTimeline entry, in the same order) to at most liveDuration of
+// playback measured in SegmentTemplate's timescale units, starting from the
+// live edge. liveDuration <= 0 means no cap.
+func capDASHSegmentsByDuration(mediaParts []*extractors.Part, timeline []dashTimelineEntry, timescale int, liveDuration time.Duration) []*extractors.Part {
+	if liveDuration <= 0 {
+		return mediaParts
+	}
+
+	durations := make([]int, 0, len(mediaParts))
+	for _, s := range timeline {
+		for i := 0; i <= s.R; i++ {
+			durations = append(durations, s.D)
+		}
+	}
+	if len(durations) != len(mediaParts) {
+		return mediaParts
+	}
+
+	var total time.Duration
+	cut := 0
+	for i := len(mediaParts) - 1; i >= 0; i-- {
+		total += time.Duration(durations[i]) * time.Second / time.Duration(timescale)
+		cut = i
+		if total >= liveDuration {
+			break
+		}
+	}
+	return mediaParts[cut:]
+}
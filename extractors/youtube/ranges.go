@@ -0,0 +1,204 @@
+package youtube
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kkdai/youtube/v2"
+	"github.com/pkg/errors"
+
+	"github.com/iawia002/lux/extractors"
+	"github.com/iawia002/lux/request"
+	"github.com/iawia002/lux/utils"
+)
+
+// rangeFetchRetries is how many additional attempts fetchRangeWithRetry
+// makes after an initial failure, whether or not that failure looked like
+// URL expiry.
+const rangeFetchRetries = 2
+
+// rangeFetchBackoff is the delay before the first retry, doubled after each
+// subsequent one. It's a var, not a const, so tests can shrink it.
+var rangeFetchBackoff = 500 * time.Millisecond
+
+// defaultChunkSize follows kkdai's Size10Mb convention for how much of a
+// googlevideo.com URL to request per range.
+const defaultChunkSize = 10 * 1024 * 1024
+
+// splitIntoRanges breaks a format of the given size into chunkSize-sized
+// byte ranges so the downloader can fetch them in parallel instead of
+// serially, which is how googlevideo.com throttles single connections.
+func splitIntoRanges(size, chunkSize int64) []extractors.Range {
+	if size <= 0 || chunkSize <= 0 {
+		return nil
+	}
+
+	ranges := make([]extractors.Range, 0, size/chunkSize+1)
+	for start := int64(0); start < size; start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		ranges = append(ranges, extractors.Range{Start: start, End: end})
+	}
+	return ranges
+}
+
+// FetchPart satisfies extractors.PartFetcher, so the downloader can reach
+// it without needing anything YouTube-specific in its own signature. When
+// part.Segments is set, the ranges are fetched concurrently with a worker
+// pool sized by threadNumber, rather than one request for the whole file,
+// since googlevideo.com throttles single connections hard. The assembled
+// result's size is checked against part.Size before it's returned.
+func (e *extractor) FetchPart(part *extractors.Part, threadNumber int) (string, error) {
+	if len(part.Segments) == 0 {
+		body, err := request.Get(part.URL, referer, nil)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return body, nil
+	}
+
+	if threadNumber <= 0 {
+		threadNumber = 1
+	}
+
+	chunks := make([]string, len(part.Segments))
+	errs := make([]error, len(part.Segments))
+	source := e.partSourceFor(part)
+	refresher := newPartURLRefresher(e, source.video, source.format)
+
+	wgp := utils.NewWaitGroupPool(threadNumber)
+	for i, rng := range part.Segments {
+		wgp.Add()
+		go func(i int, rng extractors.Range) {
+			defer wgp.Done()
+			chunks[i], errs[i] = fetchRangeWithRetry(part.URL, rng, refresher)
+		}(i, rng)
+	}
+	wgp.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if refreshed, ok := refresher.refreshedURL(); ok {
+		part.URL = refreshed
+	}
+
+	body := strings.Join(chunks, "")
+	if part.Size > 0 && int64(len(body)) != part.Size {
+		return "", errors.Errorf("downloaded %d bytes, want %d", len(body), part.Size)
+	}
+	return body, nil
+}
+
+// partURLRefresher re-resolves the stream URL via GetStreamURL at most once
+// across every goroutine downloading a part's ranges, since they'd all hit
+// the same expired URL and there's no point asking kkdai to re-sign it N
+// times. It never touches the shared *extractors.Part directly, so callers
+// racing to read its old URL while another goroutine refreshes it isn't a
+// concern; FetchPart applies the refreshed URL once all ranges are done.
+type partURLRefresher struct {
+	once    sync.Once
+	resolve func()
+	url     string
+	err     error
+}
+
+func newPartURLRefresher(e *extractor, video *youtube.Video, f *youtube.Format) *partURLRefresher {
+	r := &partURLRefresher{}
+	if video == nil || f == nil {
+		r.resolve = func() {
+			r.err = errors.New("no source recorded for this part; cannot refresh its expired URL")
+		}
+		return r
+	}
+	r.resolve = func() {
+		r.url, r.err = e.refreshPartURL(video, f)
+	}
+	return r
+}
+
+// partSource records the *youtube.Video/*youtube.Format a Part was built
+// from, so FetchPart can re-resolve an expired stream URL via
+// refreshPartURL without extractors.Part needing to know anything
+// YouTube-specific.
+type partSource struct {
+	video  *youtube.Video
+	format *youtube.Format
+}
+
+// trackPartSource associates part with the video/format it was generated
+// from, looked up later by partSourceFor when FetchPart needs to refresh
+// part's URL.
+func (e *extractor) trackPartSource(part *extractors.Part, video *youtube.Video, f *youtube.Format) {
+	e.partSourcesMu.Lock()
+	defer e.partSourcesMu.Unlock()
+	e.partSources[part] = partSource{video: video, format: f}
+}
+
+// partSourceFor returns the video/format part was generated from, or the
+// zero value if none was recorded (e.g. a part built directly in a test).
+func (e *extractor) partSourceFor(part *extractors.Part) partSource {
+	e.partSourcesMu.Lock()
+	defer e.partSourcesMu.Unlock()
+	return e.partSources[part]
+}
+
+// refresh returns the re-resolved stream URL, doing the actual re-resolution
+// only once no matter how many concurrent range fetches call it.
+func (r *partURLRefresher) refresh() (string, error) {
+	r.once.Do(r.resolve)
+	return r.url, r.err
+}
+
+// refreshedURL reports the URL a successful refresh settled on, if any.
+func (r *partURLRefresher) refreshedURL() (string, bool) {
+	return r.url, r.url != ""
+}
+
+// isExpiredPartURLError reports whether err looks like the 403 a
+// googlevideo.com URL returns once it expires, as opposed to a transient
+// network error or a server-side failure that refreshing the URL won't fix.
+func isExpiredPartURLError(err error) bool {
+	return strings.Contains(err.Error(), "403")
+}
+
+// fetchRangeWithRetry fetches one byte range of partURL, retrying up to
+// rangeFetchRetries more times with a backoff between attempts. A 403
+// (the googlevideo.com URL expiring) also triggers a one-time refresh via
+// refresher before the next attempt; any other error is just retried against
+// the same URL, since refreshing wouldn't help it.
+func fetchRangeWithRetry(partURL string, rng extractors.Range, refresher *partURLRefresher) (string, error) {
+	headers := map[string]string{"Range": fmt.Sprintf("bytes=%d-%d", rng.Start, rng.End)}
+
+	url := partURL
+	backoff := rangeFetchBackoff
+	var lastErr error
+	for attempt := 0; attempt <= rangeFetchRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		body, err := request.Get(url, referer, headers)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		if isExpiredPartURLError(err) {
+			refreshed, refreshErr := refresher.refresh()
+			if refreshErr != nil {
+				return "", errors.WithStack(err)
+			}
+			url = refreshed
+		}
+	}
+	return "", errors.WithStack(lastErr)
+}
@@ -0,0 +1,149 @@
+package youtube
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iawia002/lux/extractors"
+)
+
+func init() {
+	// Keep the retry loop fast in tests; the real backoff matters in
+	// production, not here.
+	rangeFetchBackoff = time.Millisecond
+}
+
+// rangeServer serves content in full when no Range header is present, and
+// the requested byte range otherwise, the way googlevideo.com does.
+func rangeServer(content string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rng := r.Header.Get("Range")
+		if rng == "" {
+			_, _ = w.Write([]byte(content))
+			return
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if end >= len(content) {
+			end = len(content) - 1
+		}
+		_, _ = w.Write([]byte(content[start : end+1]))
+	}))
+}
+
+func TestFetchPartRangedSuccess(t *testing.T) {
+	const content = "0123456789ABCDEFGHIJ"
+	srv := rangeServer(content)
+	defer srv.Close()
+
+	part := &extractors.Part{
+		URL:      srv.URL,
+		Size:     int64(len(content)),
+		Segments: splitIntoRanges(int64(len(content)), 10),
+	}
+
+	e := &extractor{}
+	got, err := e.FetchPart(part, 4)
+	if err != nil {
+		t.Fatalf("FetchPart() error = %v", err)
+	}
+	if got != content {
+		t.Errorf("FetchPart() = %q, want %q", got, content)
+	}
+}
+
+func TestFetchPartSizeMismatchError(t *testing.T) {
+	const content = "0123456789"
+	srv := rangeServer(content)
+	defer srv.Close()
+
+	part := &extractors.Part{
+		URL:      srv.URL,
+		Size:     int64(len(content)) + 1,
+		Segments: splitIntoRanges(int64(len(content)), 5),
+	}
+
+	e := &extractor{}
+	if _, err := e.FetchPart(part, 2); err == nil {
+		t.Fatal("FetchPart() error = nil, want a size-mismatch error")
+	}
+}
+
+func TestFetchRangeWithRetryRefreshesOnFailure(t *testing.T) {
+	const content = "0123456789"
+	expired := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer expired.Close()
+	fresh := rangeServer(content)
+	defer fresh.Close()
+
+	refresher := &partURLRefresher{resolve: func() {}}
+	refresher.url = fresh.URL
+
+	got, err := fetchRangeWithRetry(expired.URL, extractors.Range{Start: 0, End: 3}, refresher)
+	if err != nil {
+		t.Fatalf("fetchRangeWithRetry() error = %v", err)
+	}
+	if got != content[:4] {
+		t.Errorf("fetchRangeWithRetry() = %q, want %q", got, content[:4])
+	}
+}
+
+func TestFetchRangeWithRetryPropagatesRefreshError(t *testing.T) {
+	expired := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer expired.Close()
+
+	refresher := &partURLRefresher{err: fmt.Errorf("refresh failed"), resolve: func() {}}
+
+	if _, err := fetchRangeWithRetry(expired.URL, extractors.Range{Start: 0, End: 3}, refresher); err == nil {
+		t.Fatal("fetchRangeWithRetry() error = nil, want error when the refresh itself fails")
+	}
+}
+
+func TestFetchRangeWithRetryRetriesNon403WithoutRefreshing(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("0123"))
+	}))
+	defer srv.Close()
+
+	refreshCalled := false
+	refresher := &partURLRefresher{resolve: func() { refreshCalled = true }}
+
+	got, err := fetchRangeWithRetry(srv.URL, extractors.Range{Start: 0, End: 3}, refresher)
+	if err != nil {
+		t.Fatalf("fetchRangeWithRetry() error = %v", err)
+	}
+	if got != "0123" {
+		t.Errorf("fetchRangeWithRetry() = %q, want %q", got, "0123")
+	}
+	if refreshCalled {
+		t.Error("fetchRangeWithRetry() refreshed the URL on a non-403 error, want it to retry in place instead")
+	}
+}
+
+func TestFetchRangeWithRetryGivesUpAfterRetriesExhausted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	refresher := &partURLRefresher{resolve: func() {}}
+	if _, err := fetchRangeWithRetry(srv.URL, extractors.Range{Start: 0, End: 3}, refresher); err == nil {
+		t.Fatal("fetchRangeWithRetry() error = nil, want error once a persistent non-403 failure exhausts its retries")
+	}
+}
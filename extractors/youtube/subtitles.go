@@ -0,0 +1,215 @@
+package youtube
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/kkdai/youtube/v2"
+	"github.com/pkg/errors"
+
+	"github.com/iawia002/lux/extractors"
+	"github.com/iawia002/lux/request"
+	"github.com/iawia002/lux/utils"
+)
+
+// captionStreams turns video.CaptionTracks into selectable subtitle
+// streams, filtered to option.SubtitleLanguages (BCP-47 codes) when it's
+// non-empty. Auto-generated tracks (kind=asr) are included the same way as
+// regular ones, since they're both just entries in CaptionTracks.
+//
+// Each track is offered as WebVTT by default; setting option.SubtitleFormat
+// to "srt" converts it from SRV3 in pure Go instead, since YouTube's own
+// caption endpoint has no SRT format. option.SubtitleTranslations requests
+// additional on-the-fly translations via the tlang= parameter, restricted
+// to the languages the video actually offers translation into.
+func captionStreams(video *youtube.Video, option extractors.Options) map[string]*extractors.Stream {
+	streams := make(map[string]*extractors.Stream, len(video.CaptionTracks))
+	for _, track := range video.CaptionTracks {
+		if !wantLanguage(track.LanguageCode, option.SubtitleLanguages) {
+			continue
+		}
+
+		id := "sub-" + track.LanguageCode
+		if track.Kind == "asr" {
+			id += "-auto"
+		}
+		quality := track.Name.SimpleText
+		if track.Kind == "asr" {
+			quality += " (auto-generated)"
+		}
+
+		part := &extractors.Part{URL: vttURL(track.BaseURL), Ext: "vtt"}
+		if option.SubtitleFormat == "srt" {
+			if body, err := convertCaptionTrack(track, "srt"); err == nil {
+				part = &extractors.Part{Body: body, Ext: "srt"}
+			}
+		}
+
+		streams[id] = &extractors.Stream{
+			ID:      id,
+			Parts:   []*extractors.Part{part},
+			Quality: quality,
+			Ext:     part.Ext,
+			NeedMux: false,
+		}
+
+		for _, target := range option.SubtitleTranslations {
+			translated, err := translatedCaptionStream(video, track, target)
+			if err != nil {
+				continue
+			}
+			streams[translated.ID] = translated
+		}
+	}
+	return streams
+}
+
+// translatedCaptionStream builds an on-the-fly translation of baseTrack
+// into targetLang by appending the tlang= query parameter, restricted to
+// languages present in video.TranslationLanguages.
+func translatedCaptionStream(video *youtube.Video, baseTrack youtube.CaptionTrack, targetLang string) (*extractors.Stream, error) {
+	supported := false
+	for _, lang := range video.TranslationLanguages {
+		if lang.LanguageCode == targetLang {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, errors.Errorf("translation to %q is not offered for this video", targetLang)
+	}
+
+	id := fmt.Sprintf("sub-%s-translated-%s", baseTrack.LanguageCode, targetLang)
+	return &extractors.Stream{
+		ID:      id,
+		Parts:   []*extractors.Part{{URL: vttURL(baseTrack.BaseURL) + "&tlang=" + targetLang}},
+		Quality: fmt.Sprintf("%s (translated from %s)", targetLang, baseTrack.LanguageCode),
+		Ext:     "vtt",
+		NeedMux: false,
+	}, nil
+}
+
+// vttURL appends fmt=vtt to a caption track's baseUrl, replacing any
+// existing fmt parameter (tracks default to an internal XML format).
+func vttURL(baseURL string) string {
+	if strings.Contains(baseURL, "fmt=") {
+		return utils.ReplaceQueryParam(baseURL, "fmt", "vtt")
+	}
+	sep := "?"
+	if strings.Contains(baseURL, "?") {
+		sep = "&"
+	}
+	return baseURL + sep + "fmt=vtt"
+}
+
+func wantLanguage(code string, languages []string) bool {
+	if len(languages) == 0 {
+		return true
+	}
+	for _, l := range languages {
+		if l == code {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchSRV3 downloads a track in YouTube's SRV3 XML format, used as the
+// source for converting to SRT when a plain VTT isn't good enough (SRV3
+// carries per-word timing and styling that VTT drops).
+func fetchSRV3(baseURL string) (string, error) {
+	url := baseURL
+	if strings.Contains(url, "fmt=") {
+		url = utils.ReplaceQueryParam(url, "fmt", "srv3")
+	} else {
+		url += "?fmt=srv3"
+	}
+	body, err := request.Get(url, referer, nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return body, nil
+}
+
+// srv3Text is the minimal subset of SRV3 XML we need to reconstruct cue
+// timing and text for conversion to SRT/WebVTT.
+type srv3Text struct {
+	Start    float64 `xml:"t,attr"`
+	Duration float64 `xml:"d,attr"`
+	Body     string  `xml:",chardata"`
+}
+
+type srv3Document struct {
+	Texts []srv3Text `xml:"body>p"`
+}
+
+// srv3ToSRT converts an SRV3 document (as returned by fetchSRV3) into SRT.
+// Timestamps in SRV3 are milliseconds; SRT wants HH:MM:SS,mmm.
+func srv3ToSRT(doc srv3Document) string {
+	var b strings.Builder
+	for i, t := range doc.Texts {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n",
+			i+1,
+			srtTimestamp(t.Start),
+			srtTimestamp(t.Start+t.Duration),
+			strings.TrimSpace(t.Body),
+		)
+	}
+	return b.String()
+}
+
+// srv3ToVTT converts an SRV3 document into WebVTT.
+func srv3ToVTT(doc srv3Document) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, t := range doc.Texts {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n",
+			vttTimestamp(t.Start),
+			vttTimestamp(t.Start+t.Duration),
+			strings.TrimSpace(t.Body),
+		)
+	}
+	return b.String()
+}
+
+func srtTimestamp(ms float64) string {
+	return formatTimestamp(ms, ",")
+}
+
+func vttTimestamp(ms float64) string {
+	return formatTimestamp(ms, ".")
+}
+
+func formatTimestamp(ms float64, fracSep string) string {
+	total := int64(ms)
+	hours := total / 3_600_000
+	minutes := (total % 3_600_000) / 60_000
+	seconds := (total % 60_000) / 1_000
+	millis := total % 1_000
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", hours, minutes, seconds, fracSep, millis)
+}
+
+// convertCaptionTrack downloads track's SRV3 source and converts it to srt
+// or vtt, since SRV3's per-word timing is pure-Go-convertible without
+// shelling out to anything.
+func convertCaptionTrack(track youtube.CaptionTrack, format string) (string, error) {
+	body, err := fetchSRV3(track.BaseURL)
+	if err != nil {
+		return "", err
+	}
+
+	var doc srv3Document
+	if err := xml.Unmarshal([]byte(body), &doc); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	switch format {
+	case "srt":
+		return srv3ToSRT(doc), nil
+	case "vtt":
+		return srv3ToVTT(doc), nil
+	default:
+		return "", errors.Errorf("unsupported caption format %q", format)
+	}
+}
@@ -0,0 +1,196 @@
+package youtube
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kkdai/youtube/v2"
+
+	"github.com/iawia002/lux/extractors"
+)
+
+func TestWantLanguage(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		languages []string
+		want      bool
+	}{
+		{"no filter accepts everything", "en", nil, true},
+		{"matches one of several", "es", []string{"en", "es"}, true},
+		{"rejects unlisted language", "fr", []string{"en", "es"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wantLanguage(tt.code, tt.languages); got != tt.want {
+				t.Errorf("wantLanguage(%q, %v) = %v, want %v", tt.code, tt.languages, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVTTURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+	}{
+		{"no query params yet", "https://example.com/caption", "https://example.com/caption?fmt=vtt"},
+		{"existing unrelated query param", "https://example.com/caption?lang=en", "https://example.com/caption?lang=en&fmt=vtt"},
+		{"replaces existing fmt param", "https://example.com/caption?fmt=srv3", "https://example.com/caption?fmt=vtt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vttURL(tt.baseURL); got != tt.want {
+				t.Errorf("vttURL(%q) = %q, want %q", tt.baseURL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		ms      float64
+		fracSep string
+		want    string
+	}{
+		{"srt separator", 3_723_045, ",", "01:02:03,045"},
+		{"vtt separator", 3_723_045, ".", "01:02:03.045"},
+		{"zero", 0, ",", "00:00:00,000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatTimestamp(tt.ms, tt.fracSep); got != tt.want {
+				t.Errorf("formatTimestamp(%v, %q) = %q, want %q", tt.ms, tt.fracSep, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSrtAndVttTimestamp(t *testing.T) {
+	if got := srtTimestamp(1500); got != "00:00:01,500" {
+		t.Errorf("srtTimestamp(1500) = %q, want %q", got, "00:00:01,500")
+	}
+	if got := vttTimestamp(1500); got != "00:00:01.500" {
+		t.Errorf("vttTimestamp(1500) = %q, want %q", got, "00:00:01.500")
+	}
+}
+
+func TestSrv3ToSRT(t *testing.T) {
+	doc := srv3Document{Texts: []srv3Text{
+		{Start: 0, Duration: 1500, Body: "Hello"},
+		{Start: 1500, Duration: 1000, Body: "World"},
+	}}
+	want := "1\n00:00:00,000 --> 00:00:01,500\nHello\n\n" +
+		"2\n00:00:01,500 --> 00:00:02,500\nWorld\n\n"
+	if got := srv3ToSRT(doc); got != want {
+		t.Errorf("srv3ToSRT() = %q, want %q", got, want)
+	}
+}
+
+func TestSrv3ToVTT(t *testing.T) {
+	doc := srv3Document{Texts: []srv3Text{
+		{Start: 0, Duration: 1500, Body: "Hello"},
+	}}
+	want := "WEBVTT\n\n00:00:00.000 --> 00:00:01.500\nHello\n\n"
+	if got := srv3ToVTT(doc); got != want {
+		t.Errorf("srv3ToVTT() = %q, want %q", got, want)
+	}
+}
+
+func TestFetchSRV3AppendsFormatParam(t *testing.T) {
+	var gotURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		_, _ = w.Write([]byte(`<timedtext><body><p t="0" d="1000">hi</p></body></timedtext>`))
+	}))
+	defer srv.Close()
+
+	body, err := fetchSRV3(srv.URL)
+	if err != nil {
+		t.Fatalf("fetchSRV3() error = %v", err)
+	}
+	if gotURL != "/?fmt=srv3" {
+		t.Errorf("fetchSRV3() requested %q, want fmt=srv3 appended", gotURL)
+	}
+	if body == "" {
+		t.Error("fetchSRV3() returned empty body")
+	}
+}
+
+func TestConvertCaptionTrack(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<timedtext><body><p t="0" d="1500">Hello</p></body></timedtext>`))
+	}))
+	defer srv.Close()
+
+	track := youtube.CaptionTrack{BaseURL: srv.URL, LanguageCode: "en"}
+
+	srt, err := convertCaptionTrack(track, "srt")
+	if err != nil {
+		t.Fatalf("convertCaptionTrack(srt) error = %v", err)
+	}
+	want := "1\n00:00:00,000 --> 00:00:01,500\nHello\n\n"
+	if srt != want {
+		t.Errorf("convertCaptionTrack(srt) = %q, want %q", srt, want)
+	}
+
+	if _, err := convertCaptionTrack(track, "ass"); err == nil {
+		t.Error("convertCaptionTrack(ass) error = nil, want error for an unsupported format")
+	}
+}
+
+func TestCaptionStreamsFiltersByLanguage(t *testing.T) {
+	video := &youtube.Video{
+		CaptionTracks: []youtube.CaptionTrack{
+			{LanguageCode: "en", BaseURL: "https://example.com/en", Name: youtube.CaptionName{SimpleText: "English"}},
+			{LanguageCode: "es", BaseURL: "https://example.com/es", Name: youtube.CaptionName{SimpleText: "Spanish"}},
+		},
+	}
+
+	streams := captionStreams(video, extractors.Options{SubtitleLanguages: []string{"es"}})
+	if len(streams) != 1 {
+		t.Fatalf("captionStreams() returned %d streams, want 1", len(streams))
+	}
+	if _, ok := streams["sub-es"]; !ok {
+		t.Errorf("captionStreams() = %v, want key %q", streams, "sub-es")
+	}
+}
+
+func TestCaptionStreamsMarksAutoGenerated(t *testing.T) {
+	video := &youtube.Video{
+		CaptionTracks: []youtube.CaptionTrack{
+			{LanguageCode: "en", Kind: "asr", BaseURL: "https://example.com/en", Name: youtube.CaptionName{SimpleText: "English"}},
+		},
+	}
+
+	streams := captionStreams(video, extractors.Options{})
+	stream, ok := streams["sub-en-auto"]
+	if !ok {
+		t.Fatalf("captionStreams() = %v, want key %q", streams, "sub-en-auto")
+	}
+	if stream.Quality != "English (auto-generated)" {
+		t.Errorf("stream.Quality = %q, want %q", stream.Quality, "English (auto-generated)")
+	}
+}
+
+func TestTranslatedCaptionStream(t *testing.T) {
+	video := &youtube.Video{
+		TranslationLanguages: []youtube.CaptionLanguage{{LanguageCode: "de"}},
+	}
+	track := youtube.CaptionTrack{LanguageCode: "en", BaseURL: "https://example.com/en"}
+
+	stream, err := translatedCaptionStream(video, track, "de")
+	if err != nil {
+		t.Fatalf("translatedCaptionStream() error = %v", err)
+	}
+	if stream.ID != "sub-en-translated-de" {
+		t.Errorf("stream.ID = %q, want %q", stream.ID, "sub-en-translated-de")
+	}
+
+	if _, err := translatedCaptionStream(video, track, "fr"); err == nil {
+		t.Error("translatedCaptionStream() error = nil, want error for an unsupported target language")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/kkdai/youtube/v2"
 	"github.com/pkg/errors"
@@ -27,18 +28,54 @@ func init() {
 const referer = "https://www.youtube.com"
 
 type extractor struct {
-	client *youtube.Client
+	client       *youtube.Client
+	clientOrder  []string
+	auth         *auth
+	sponsorBlock *sponsorBlockClient
+	chapters     *chaptersClient
+
+	partSourcesMu sync.Mutex
+	partSources   map[*extractors.Part]partSource
 }
 
+// compile-time check that extractor satisfies extractors.PartFetcher.
+var _ extractors.PartFetcher = (*extractor)(nil)
+
 type youtubeTransport struct {
 	base    http.RoundTripper
 	headers map[string]string
+	auth    *auth
 }
 
 func (t *youtubeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	for key, value := range t.headers {
 		req.Header.Set(key, value)
 	}
+
+	if t.auth == nil || t.auth.oauth == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	resp, err := t.withBearer(req, false)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.GetBody == nil {
+		return resp, err
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return resp, nil
+	}
+	req.Body = body
+	return t.withBearer(req, true)
+}
+
+// withBearer attaches the OAuth2 Bearer token to req and replays it,
+// forcing a token refresh first when forceRefresh is set (used on 401).
+func (t *youtubeTransport) withBearer(req *http.Request, forceRefresh bool) (*http.Response, error) {
+	token, err := t.auth.oauth.token(forceRefresh)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
 	return t.base.RoundTrip(req)
 }
 
@@ -85,14 +122,16 @@ func getVisitorId() (string, error) {
 	return visitor, nil
 }
 
-// New returns a youtube extractor.
-func New() extractors.Extractor {
+// New returns a youtube extractor. By default it tries the Android and iOS
+// InnerTube clients before falling back to Web; pass WithClients to change
+// the order or restrict it to a subset.
+func New(opts ...Option) extractors.Extractor {
 	visitorId, err := getVisitorId()
 	if err != nil {
 		panic(fmt.Sprintf("failed to get visitorId: %v", err))
 	}
 
-	return &extractor{
+	e := &extractor{
 		client: &youtube.Client{
 			HTTPClient: &http.Client{
 				Transport: &youtubeTransport{
@@ -105,17 +144,93 @@ func New() extractors.Extractor {
 				},
 			},
 		},
+		clientOrder:  defaultClientOrder,
+		partSources:  make(map[*extractors.Part]partSource),
+		chapters:     &chaptersClient{cache: make(map[string][]extractors.Chapter)},
+		sponsorBlock: &sponsorBlockClient{cache: make(map[string][]extractors.Segment)},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// clientFor returns a copy of e.client configured to use info, so rotating
+// clients never mutates the shared *youtube.Client other goroutines (e.g.
+// concurrent playlist items) may be using at the same time.
+func (e *extractor) clientFor(info youtube.ClientInfo) *youtube.Client {
+	c := *e.client
+	c.Client = info
+	return &c
+}
+
+// getVideoWithFallback fetches a video by rotating through e.clientOrder,
+// moving on to the next client on a 403 response or a result with no
+// formats. The Web client is the only one that needs the decipher/nsig
+// transform; kkdai's client re-evaluates and caches that from base.js for us.
+func (e *extractor) getVideoWithFallback(url string) (*youtube.Video, error) {
+	return e.withClientFallback(func(client *youtube.Client) (*youtube.Video, error) {
+		return client.GetVideo(url)
+	})
+}
+
+// getPlaylistEntryWithFallback is getVideoWithFallback for a single
+// playlist entry, so playlist downloads get the same Android/iOS-first
+// rotation as single-video downloads.
+func (e *extractor) getPlaylistEntryWithFallback(entry *youtube.PlaylistEntry) (*youtube.Video, error) {
+	return e.withClientFallback(func(client *youtube.Client) (*youtube.Video, error) {
+		return client.VideoFromPlaylistEntry(entry)
+	})
+}
+
+// withClientFallback tries fetch with each client in e.clientOrder in turn,
+// moving on to the next client on a 403 response or a result with no
+// formats. A result with no formats but a live HLS/DASH manifest is accepted
+// as-is rather than treated as a failure, since that's the normal shape of a
+// livestream or premiere response.
+func (e *extractor) withClientFallback(fetch func(*youtube.Client) (*youtube.Video, error)) (*youtube.Video, error) {
+	var lastErr error
+	for _, name := range e.clientOrder {
+		info, ok := clientInfoByName(name)
+		if !ok {
+			continue
+		}
+
+		video, err := fetch(e.clientFor(info))
+		if err == nil && (len(video.Formats) > 0 || video.HLSManifestURL != "" || video.DASHManifestURL != "") {
+			return video, nil
+		}
+		if err != nil {
+			lastErr = err
+			if !isRotatableError(err) {
+				return nil, errors.WithStack(err)
+			}
+			continue
+		}
+		lastErr = errors.Errorf("client %q returned no formats", name)
 	}
+	return nil, errors.WithStack(lastErr)
+}
+
+// isRotatableError reports whether err looks like a client-specific block
+// (e.g. a 403 from Android/iOS) that's worth retrying with the next client,
+// as opposed to a permanent failure like "video unavailable".
+func isRotatableError(err error) bool {
+	return strings.Contains(err.Error(), "403") || strings.Contains(err.Error(), "empty")
 }
 
 // Extract is the main function to extract the data.
 func (e *extractor) Extract(url string, option extractors.Options) ([]*extractors.Data, error) {
+	if e.auth != nil && e.auth.cookiesErr != nil {
+		return nil, errors.Wrap(e.auth.cookiesErr, "loading cookies for WithAuth")
+	}
+
 	if !option.Playlist {
-		video, err := e.client.GetVideo(url)
+		video, err := e.getVideoWithFallback(url)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
-		return []*extractors.Data{e.youtubeDownload(url, video)}, nil
+		return []*extractors.Data{e.youtubeDownload(url, video, option)}, nil
 	}
 
 	playlist, err := e.client.GetPlaylist(url)
@@ -135,11 +250,11 @@ func (e *extractor) Extract(url string, option extractors.Options) ([]*extractor
 		wgp.Add()
 		go func(index int, entry *youtube.PlaylistEntry, extractedData []*extractors.Data) {
 			defer wgp.Done()
-			video, err := e.client.VideoFromPlaylistEntry(entry)
+			video, err := e.getPlaylistEntryWithFallback(entry)
 			if err != nil {
 				return
 			}
-			extractedData[index] = e.youtubeDownload(url, video)
+			extractedData[index] = e.youtubeDownload(url, video, option)
 		}(dataIndex, videoEntry, extractedData)
 		dataIndex++
 	}
@@ -148,7 +263,11 @@ func (e *extractor) Extract(url string, option extractors.Options) ([]*extractor
 }
 
 // youtubeDownload download function for single url
-func (e *extractor) youtubeDownload(url string, video *youtube.Video) *extractors.Data {
+func (e *extractor) youtubeDownload(url string, video *youtube.Video, option extractors.Options) *extractors.Data {
+	if option.Live && (video.HLSManifestURL != "" || video.DASHManifestURL != "") {
+		return e.youtubeDownloadLive(url, video, option)
+	}
+
 	streams := make(map[string]*extractors.Stream, len(video.Formats))
 	audioCache := make(map[string]*extractors.Part)
 
@@ -196,30 +315,67 @@ func (e *extractor) youtubeDownload(url string, video *youtube.Video) *extractor
 		streams[itag] = stream
 	}
 
-	return &extractors.Data{
+	if option.Subtitles {
+		for id, s := range captionStreams(video, option) {
+			streams[id] = s
+		}
+	}
+
+	data := &extractors.Data{
 		Site:    "YouTube youtube.com",
 		Title:   video.Title,
 		Type:    "video",
 		Streams: streams,
 		URL:     url,
 	}
+	e.addChaptersAndSkipSegments(data, video, option)
+	return data
+}
+
+// addChaptersAndSkipSegments fills in Data.Chapters from InnerTube's own
+// chapter markers when option.Chapters is set, and Data.SkipSegments from
+// SponsorBlock when option.SponsorBlock is set. Both are opt-in: neither
+// fires a network request for callers who never asked for the feature.
+// Failures from either are swallowed by their respective helpers, so a
+// network outage in one never fails the extraction.
+func (e *extractor) addChaptersAndSkipSegments(data *extractors.Data, video *youtube.Video, option extractors.Options) {
+	if option.Chapters {
+		data.Chapters = e.chapters.chaptersFor(video.ID, video.Duration)
+	}
+	if option.SponsorBlock {
+		data.SkipSegments = e.sponsorBlock.segments(video.ID, option.SponsorBlockCategories)
+	}
 }
 
 func (e *extractor) genPartByFormat(video *youtube.Video, f *youtube.Format) (*extractors.Part, error) {
 	ext := getStreamExt(f.MimeType)
-	url, err := e.client.GetStreamURL(video, f)
+	streamURL, err := e.client.GetStreamURL(video, f)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	size := f.ContentLength
 	if size == 0 {
-		size, _ = request.Size(url, referer)
+		size, _ = request.Size(streamURL, referer)
+	}
+	part := &extractors.Part{
+		URL:      streamURL,
+		Size:     size,
+		Ext:      ext,
+		Segments: splitIntoRanges(size, defaultChunkSize),
+	}
+	e.trackPartSource(part, video, f)
+	return part, nil
+}
+
+// refreshPartURL re-resolves a format's stream URL via GetStreamURL, for use
+// when a range request comes back 403 because the previously-resolved URL
+// expired mid-download.
+func (e *extractor) refreshPartURL(video *youtube.Video, f *youtube.Format) (string, error) {
+	streamURL, err := e.client.GetStreamURL(video, f)
+	if err != nil {
+		return "", errors.WithStack(err)
 	}
-	return &extractors.Part{
-		URL:  url,
-		Size: size,
-		Ext:  ext,
-	}, nil
+	return streamURL, nil
 }
 
 func getVideoAudio(v *youtube.Video, mimeType string) (*youtube.Format, error) {